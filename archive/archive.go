@@ -0,0 +1,40 @@
+// Package archive defines the index of files that have already been
+// uploaded, so repeat runs can tell which local files are new, changed, or
+// have drifted from their desired storage options.
+package archive
+
+import (
+	"context"
+	"time"
+)
+
+// FileRecord describes a single archived file, enough to detect whether the
+// local copy has changed, or the desired storage options have drifted,
+// since the last run.
+type FileRecord struct {
+	Key                  string    `json:"key"`
+	Size                 int64     `json:"size"`
+	ModTime              time.Time `json:"mod_time"`
+	Hash                 string    `json:"hash"` // SHA256 hex digest of the file contents
+	StorageClass         string    `json:"storage_class,omitempty"`
+	ServerSideEncryption string    `json:"sse,omitempty"`
+	SSEKMSKeyID          string    `json:"sse_kms_key_id,omitempty"`
+	SSECustomerKeyHash   string    `json:"sse_c_key_hash,omitempty"` // SHA256 hex digest of the SSE-C key, never the key itself
+	UploadedAt           time.Time `json:"uploaded_at,omitempty"`
+}
+
+// Store is an index of archived files, keyed by their destination key. It
+// must be safe for concurrent use by multiple upload workers.
+type Store interface {
+	// Get returns the archived record for key, or ok=false if none exists.
+	Get(ctx context.Context, key string) (record FileRecord, ok bool, err error)
+
+	// Put records (or updates) the archived metadata for key.
+	Put(ctx context.Context, record FileRecord) error
+
+	// All returns every record currently in the store, keyed by its key.
+	All(ctx context.Context) (map[string]FileRecord, error)
+
+	// Close releases any resources held by the store.
+	Close() error
+}
@@ -0,0 +1,112 @@
+// Package jsonstore implements archive.Store by rewriting a single JSON
+// file on every write. It is the original archive format: simple, but it
+// does not scale past a few tens of thousands of entries since every Put
+// rewrites the whole file.
+package jsonstore
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/Tetuwo181/S3archiver/archive"
+)
+
+// legacyFile is the pre-hash-tracking archive format, a flat list of keys
+// with no size/mtime/hash metadata.
+type legacyFile struct {
+	Files []string `json:"files"`
+}
+
+// file is the on-disk representation of the store.
+type file struct {
+	Files map[string]archive.FileRecord `json:"files"`
+}
+
+// Store is a JSON-file-backed archive.Store.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	files map[string]archive.FileRecord
+}
+
+// Open loads filename, migrating the legacy []string format if necessary,
+// and returns a Store backed by it. A missing file is treated as an empty
+// archive.
+func Open(filename string) (*Store, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Store{path: filename, files: map[string]archive.FileRecord{}}, nil
+		}
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err == nil {
+		if f.Files == nil {
+			f.Files = map[string]archive.FileRecord{}
+		}
+		return &Store{path: filename, files: f.Files}, nil
+	}
+
+	// Fall back to the legacy flat-list format and migrate it. Migrated
+	// entries have no recorded hash, so they will be re-uploaded once to
+	// backfill it.
+	var legacy legacyFile
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, err
+	}
+	files := make(map[string]archive.FileRecord, len(legacy.Files))
+	for _, key := range legacy.Files {
+		files[key] = archive.FileRecord{Key: key}
+	}
+	log.Printf("Migrated legacy archive format (%d entries) for %s", len(legacy.Files), filename)
+	return &Store{path: filename, files: files}, nil
+}
+
+// Get returns the archived record for key, or ok=false if none exists.
+func (s *Store) Get(ctx context.Context, key string) (archive.FileRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.files[key]
+	return record, ok, nil
+}
+
+// Put records the archived metadata for key and rewrites the JSON file.
+func (s *Store) Put(ctx context.Context, record archive.FileRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[record.Key] = record
+	return s.save()
+}
+
+// save writes the current in-memory state to disk. Callers must hold s.mu.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(file{Files: s.files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0644)
+}
+
+// Close is a no-op; every Put already persists to disk.
+func (s *Store) Close() error {
+	return nil
+}
+
+// All returns every record currently in the store, keyed by its key. It is
+// used to migrate a JSON archive to another store implementation.
+func (s *Store) All(ctx context.Context) (map[string]archive.FileRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make(map[string]archive.FileRecord, len(s.files))
+	for k, v := range s.files {
+		files[k] = v
+	}
+	return files, nil
+}
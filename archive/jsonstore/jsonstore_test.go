@@ -0,0 +1,92 @@
+package jsonstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Tetuwo181/S3archiver/archive"
+)
+
+func TestOpenMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok, err := store.Get(context.Background(), "key"); err != nil || ok {
+		t.Fatalf("Get: got ok=%v, err=%v, want ok=false, err=nil", ok, err)
+	}
+}
+
+func TestPutGetAllAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	record := archive.FileRecord{
+		Key:        "a/b.txt",
+		Size:       42,
+		ModTime:    time.Now().UTC().Truncate(time.Second),
+		Hash:       "deadbeef",
+		UploadedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := store.Put(context.Background(), record); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := store.Get(context.Background(), "a/b.txt")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v, err=%v", ok, err)
+	}
+	if got.Hash != record.Hash {
+		t.Errorf("Get: hash = %q, want %q", got.Hash, record.Hash)
+	}
+	store.Close()
+
+	// Reopening should load what was persisted to disk.
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open (reload): %v", err)
+	}
+	defer reopened.Close()
+
+	all, err := reopened.All(context.Background())
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 1 || all["a/b.txt"].Hash != record.Hash {
+		t.Errorf("All: got %v, want one record with hash %q", all, record.Hash)
+	}
+}
+
+func TestOpenMigratesLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.json")
+	legacy := `{"files":["a.txt","b.txt"]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	all, err := store.All(context.Background())
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("All: got %d records, want 2", len(all))
+	}
+	if _, ok := all["a.txt"]; !ok {
+		t.Error("All: expected migrated key \"a.txt\"")
+	}
+}
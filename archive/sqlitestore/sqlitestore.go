@@ -0,0 +1,141 @@
+// Package sqlitestore implements archive.Store on top of a SQLite database,
+// using the CGO-free modernc.org/sqlite driver. Unlike jsonstore, each Put
+// is a single-row transaction rather than a full-file rewrite, so it scales
+// to large archives and supports resuming after a crash.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Tetuwo181/S3archiver/archive"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS files (
+	key            TEXT PRIMARY KEY,
+	size           INTEGER NOT NULL,
+	mod_time       TEXT NOT NULL,
+	hash           TEXT NOT NULL,
+	storage_class  TEXT NOT NULL DEFAULT '',
+	sse            TEXT NOT NULL DEFAULT '',
+	sse_kms_key_id TEXT NOT NULL DEFAULT '',
+	sse_c_key_hash TEXT NOT NULL DEFAULT '',
+	uploaded_at    TEXT NOT NULL
+);
+`
+
+// Store is a SQLite-backed archive.Store.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and
+// ensures its schema exists.
+//
+// SQLite allows only one writer at a time, so the connection pool is
+// capped at a single connection and a busy timeout is set; without both,
+// concurrent upload workers calling Put would intermittently fail with
+// "database is locked" instead of simply waiting their turn.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set busy_timeout: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize archive schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Get returns the archived record for key, or ok=false if none exists.
+func (s *Store) Get(ctx context.Context, key string) (archive.FileRecord, bool, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT key, size, mod_time, hash, storage_class, sse, sse_kms_key_id, sse_c_key_hash, uploaded_at FROM files WHERE key = ?`, key)
+
+	var record archive.FileRecord
+	var modTime, uploadedAt string
+	err := row.Scan(&record.Key, &record.Size, &modTime, &record.Hash, &record.StorageClass, &record.ServerSideEncryption, &record.SSEKMSKeyID, &record.SSECustomerKeyHash, &uploadedAt)
+	if err == sql.ErrNoRows {
+		return archive.FileRecord{}, false, nil
+	}
+	if err != nil {
+		return archive.FileRecord{}, false, err
+	}
+
+	if record.ModTime, err = time.Parse(time.RFC3339Nano, modTime); err != nil {
+		return archive.FileRecord{}, false, err
+	}
+	if record.UploadedAt, err = time.Parse(time.RFC3339Nano, uploadedAt); err != nil {
+		return archive.FileRecord{}, false, err
+	}
+	return record, true, nil
+}
+
+// Put upserts the archived metadata for key in a single transaction.
+func (s *Store) Put(ctx context.Context, record archive.FileRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO files (key, size, mod_time, hash, storage_class, sse, sse_kms_key_id, sse_c_key_hash, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			size=excluded.size, mod_time=excluded.mod_time, hash=excluded.hash,
+			storage_class=excluded.storage_class, sse=excluded.sse,
+			sse_kms_key_id=excluded.sse_kms_key_id, sse_c_key_hash=excluded.sse_c_key_hash,
+			uploaded_at=excluded.uploaded_at
+	`, record.Key, record.Size, record.ModTime.Format(time.RFC3339Nano), record.Hash,
+		record.StorageClass, record.ServerSideEncryption, record.SSEKMSKeyID, record.SSECustomerKeyHash,
+		record.UploadedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to upsert archive record for %s: %w", record.Key, err)
+	}
+
+	return tx.Commit()
+}
+
+// All returns every record currently in the store, keyed by its key.
+func (s *Store) All(ctx context.Context) (map[string]archive.FileRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, size, mod_time, hash, storage_class, sse, sse_kms_key_id, sse_c_key_hash, uploaded_at FROM files`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := map[string]archive.FileRecord{}
+	for rows.Next() {
+		var record archive.FileRecord
+		var modTime, uploadedAt string
+		if err := rows.Scan(&record.Key, &record.Size, &modTime, &record.Hash, &record.StorageClass, &record.ServerSideEncryption, &record.SSEKMSKeyID, &record.SSECustomerKeyHash, &uploadedAt); err != nil {
+			return nil, err
+		}
+		if record.ModTime, err = time.Parse(time.RFC3339Nano, modTime); err != nil {
+			return nil, err
+		}
+		if record.UploadedAt, err = time.Parse(time.RFC3339Nano, uploadedAt); err != nil {
+			return nil, err
+		}
+		files[record.Key] = record
+	}
+	return files, rows.Err()
+}
+
+// Close closes the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,100 @@
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Tetuwo181/S3archiver/archive"
+)
+
+func TestPutGetAndAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	record := archive.FileRecord{
+		Key:          "a/b.txt",
+		Size:         42,
+		ModTime:      time.Now().UTC().Truncate(time.Second),
+		Hash:         "deadbeef",
+		StorageClass: "STANDARD_IA",
+		UploadedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	if err := store.Put(context.Background(), record); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := store.Get(context.Background(), "a/b.txt")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v, err=%v", ok, err)
+	}
+	if got.Hash != record.Hash || got.StorageClass != record.StorageClass {
+		t.Errorf("Get: got %+v, want %+v", got, record)
+	}
+
+	// Put again with the same key should upsert, not duplicate.
+	record.Hash = "newhash"
+	if err := store.Put(context.Background(), record); err != nil {
+		t.Fatalf("Put (update): %v", err)
+	}
+
+	all, err := store.All(context.Background())
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("All: got %d records, want 1", len(all))
+	}
+	if all["a/b.txt"].Hash != "newhash" {
+		t.Errorf("All: hash = %q, want %q", all["a/b.txt"].Hash, "newhash")
+	}
+}
+
+func TestPutGetPreservesSSEFields(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	record := archive.FileRecord{
+		Key:                  "a/b.txt",
+		Hash:                 "deadbeef",
+		ServerSideEncryption: "aws:kms",
+		SSEKMSKeyID:          "arn:aws:kms:us-east-1:111111111111:key/abc",
+		SSECustomerKeyHash:   "cafebabe",
+		ModTime:              time.Now().UTC().Truncate(time.Second),
+		UploadedAt:           time.Now().UTC().Truncate(time.Second),
+	}
+	if err := store.Put(context.Background(), record); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok, err := store.Get(context.Background(), "a/b.txt")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v, err=%v", ok, err)
+	}
+	if got.SSEKMSKeyID != record.SSEKMSKeyID {
+		t.Errorf("Get: SSEKMSKeyID = %q, want %q", got.SSEKMSKeyID, record.SSEKMSKeyID)
+	}
+	if got.SSECustomerKeyHash != record.SSECustomerKeyHash {
+		t.Errorf("Get: SSECustomerKeyHash = %q, want %q", got.SSECustomerKeyHash, record.SSECustomerKeyHash)
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok, err := store.Get(context.Background(), "missing"); err != nil || ok {
+		t.Fatalf("Get: ok=%v, err=%v, want ok=false, err=nil", ok, err)
+	}
+}
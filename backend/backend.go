@@ -0,0 +1,49 @@
+// Package backend defines a storage-agnostic interface for archiving files,
+// so the archiving logic in main can run against S3 or a local filesystem
+// mirror without depending on either directly.
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// ObjectInfo describes metadata about a single stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass string // empty for backends with no notion of storage class
+}
+
+// PutOptions controls how an object is stored. Backends that don't support a
+// given field (e.g. the local filesystem backend has no notion of storage
+// class or server-side encryption) silently ignore it.
+type PutOptions struct {
+	ContentType          string
+	ContentDisposition   string
+	CacheControl         string
+	ACL                  string
+	StorageClass         string
+	ServerSideEncryption string // "", "AES256", or "aws:kms"
+	SSEKMSKeyID          string
+	SSECustomerKey       []byte // customer-provided key for SSE-C, read from a local file
+}
+
+// Backend is a storage target that files can be archived to.
+type Backend interface {
+	// List returns the keys of all objects currently stored in the backend.
+	List(ctx context.Context) ([]string, error)
+
+	// Put uploads the local file at localPath under key, applying opts.
+	Put(ctx context.Context, key, localPath string, opts PutOptions) error
+
+	// Stat returns metadata for an existing object. ok is false if the
+	// object does not exist.
+	Stat(ctx context.Context, key string) (info ObjectInfo, ok bool, err error)
+
+	// Get downloads the object under key to the local file at destPath,
+	// creating any missing parent directories.
+	Get(ctx context.Context, key, destPath string) error
+}
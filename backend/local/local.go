@@ -0,0 +1,99 @@
+// Package local implements backend.Backend by mirroring files into a
+// directory on the local filesystem. It is useful for testing the
+// archiving logic, air-gapped archiving, and staging uploads before they
+// go to S3.
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Tetuwo181/S3archiver/backend"
+)
+
+// Backend stores files by copying them into RootDir, preserving the key as
+// a relative path.
+type Backend struct {
+	RootDir string
+}
+
+// New builds a Backend that mirrors files into rootDir.
+func New(rootDir string) *Backend {
+	return &Backend{RootDir: rootDir}
+}
+
+// List walks RootDir and returns each file's path relative to it, using "/"
+// as the separator so keys match the S3 backend's convention.
+func (b *Backend) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(b.RootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.RootDir, path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Put copies the local file at localPath into RootDir under key. opts is
+// accepted for interface compatibility; the local filesystem has no notion
+// of storage class, ACLs, or server-side encryption, so it is ignored.
+func (b *Backend) Put(ctx context.Context, key, localPath string, opts backend.PutOptions) error {
+	return copyFile(localPath, filepath.Join(b.RootDir, filepath.FromSlash(key)))
+}
+
+// Get copies the mirrored file under key to destPath.
+func (b *Backend) Get(ctx context.Context, key, destPath string) error {
+	return copyFile(filepath.Join(b.RootDir, filepath.FromSlash(key)), destPath)
+}
+
+// Stat returns metadata for a mirrored file, or ok=false if it doesn't exist.
+func (b *Backend) Stat(ctx context.Context, key string) (backend.ObjectInfo, bool, error) {
+	path := filepath.Join(b.RootDir, filepath.FromSlash(key))
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return backend.ObjectInfo{}, false, nil
+		}
+		return backend.ObjectInfo{}, false, err
+	}
+	return backend.ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()}, true, nil
+}
+
+// copyFile copies src to dest, creating dest's parent directory if needed.
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
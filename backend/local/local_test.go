@@ -0,0 +1,92 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tetuwo181/S3archiver/backend"
+)
+
+func TestBackendPutGetStat(t *testing.T) {
+	root := t.TempDir()
+	b := New(root)
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(src, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Put(ctx, "sub/hello.txt", src, backend.PutOptions{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	info, ok, err := b.Stat(ctx, "sub/hello.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !ok {
+		t.Fatal("Stat: expected object to exist")
+	}
+	if info.Size != int64(len("hello world")) {
+		t.Errorf("Stat: size = %d, want %d", info.Size, len("hello world"))
+	}
+
+	dest := filepath.Join(t.TempDir(), "out", "hello.txt")
+	if err := b.Get(ctx, "sub/hello.txt", dest); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("Get: content = %q, want %q", data, "hello world")
+	}
+}
+
+func TestBackendStatMissing(t *testing.T) {
+	b := New(t.TempDir())
+	_, ok, err := b.Stat(context.Background(), "does/not/exist")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if ok {
+		t.Error("Stat: expected ok=false for missing object")
+	}
+}
+
+func TestBackendList(t *testing.T) {
+	root := t.TempDir()
+	b := New(root)
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(src, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(ctx, "dir/a.txt", src, backend.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := b.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "dir/a.txt" {
+		t.Errorf("List: got %v, want [dir/a.txt]", keys)
+	}
+}
+
+func TestBackendListMissingRootDir(t *testing.T) {
+	b := New(filepath.Join(t.TempDir(), "does-not-exist"))
+	keys, err := b.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("List: got %v, want empty", keys)
+	}
+}
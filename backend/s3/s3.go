@@ -0,0 +1,225 @@
+// Package s3 implements backend.Backend on top of Amazon S3 (or any
+// S3-compatible service), using a manager.Uploader for concurrent,
+// chunked multipart uploads.
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Tetuwo181/S3archiver/backend"
+)
+
+// Backend stores files as objects in an S3 bucket.
+type Backend struct {
+	Client     *s3.Client
+	Uploader   *manager.Uploader
+	Downloader *manager.Downloader
+	Bucket     string
+}
+
+// New builds a Backend whose uploads and downloads use the given multipart
+// part size (in bytes) and worker concurrency.
+func New(client *s3.Client, bucket string, partSize int64, concurrency int) *Backend {
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = concurrency
+	})
+	downloader := manager.NewDownloader(client, func(d *manager.Downloader) {
+		d.PartSize = partSize
+		d.Concurrency = concurrency
+	})
+	return &Backend{Client: client, Uploader: uploader, Downloader: downloader, Bucket: bucket}
+}
+
+// List retrieves the list of object keys in the bucket.
+func (b *Backend) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(b.Client, &s3.ListObjectsV2Input{
+		Bucket: &b.Bucket,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+	}
+	return keys, nil
+}
+
+// Put uploads the local file at localPath to the bucket under key, applying
+// the requested storage class, encryption, ACL, and metadata options.
+func (b *Backend) Put(ctx context.Context, key, localPath string, opts backend.PutOptions) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", localPath, err)
+	}
+	defer file.Close()
+
+	input := &s3.PutObjectInput{
+		Bucket: &b.Bucket,
+		Key:    &key,
+		Body:   file,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(opts.ContentDisposition)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	if opts.StorageClass != "" {
+		input.StorageClass = types.StorageClass(opts.StorageClass)
+	}
+	switch opts.ServerSideEncryption {
+	case "AES256":
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	case "aws:kms":
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		if opts.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+		}
+	}
+	if len(opts.SSECustomerKey) > 0 {
+		sum := md5.Sum(opts.SSECustomerKey)
+		input.SSECustomerAlgorithm = aws.String("AES256")
+		input.SSECustomerKey = aws.String(base64.StdEncoding.EncodeToString(opts.SSECustomerKey))
+		input.SSECustomerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	_, err = b.Uploader.Upload(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+// Stat returns metadata for an existing object, or ok=false if it doesn't exist.
+func (b *Backend) Stat(ctx context.Context, key string) (backend.ObjectInfo, bool, error) {
+	out, err := b.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &b.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return backend.ObjectInfo{}, false, nil
+		}
+		return backend.ObjectInfo{}, false, err
+	}
+
+	info := backend.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ETag:         aws.ToString(out.ETag),
+		StorageClass: string(out.StorageClass),
+	}
+	if out.LastModified != nil {
+		info.LastModified = *out.LastModified
+	}
+	return info, true, nil
+}
+
+// Get downloads the object under key to the local file at destPath using
+// concurrent ranged GETs, creating any missing parent directories. Objects
+// in Glacier or Deep Archive must be restored first with RestoreAndWait.
+func (b *Backend) Get(ctx context.Context, key, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = b.Downloader.Download(ctx, out, &s3.GetObjectInput{
+		Bucket: &b.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download %s from S3: %w", key, err)
+	}
+	return nil
+}
+
+// glacierStorageClasses are the storage classes that require an explicit
+// RestoreObject call (and a wait) before the object can be downloaded.
+var glacierStorageClasses = map[string]bool{
+	string(types.StorageClassGlacier):     true,
+	string(types.StorageClassDeepArchive): true,
+}
+
+// IsRestoreRequired reports whether an object in storageClass must be
+// restored out of cold storage before it can be downloaded.
+func IsRestoreRequired(storageClass string) bool {
+	return glacierStorageClasses[storageClass]
+}
+
+// RestoreAndWait issues a Glacier/Deep Archive RestoreObject request for key
+// if needed and polls HeadObject until the restoration completes. It gives
+// up after maxAttempts polls, since a restore can take hours; callers should
+// re-run later if it returns an error.
+func (b *Backend) RestoreAndWait(ctx context.Context, key string, pollInterval time.Duration, maxAttempts int) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		out, err := b.Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &b.Bucket, Key: &key})
+		if err != nil {
+			return fmt.Errorf("failed to check restore status for %s: %w", key, err)
+		}
+
+		restore := aws.ToString(out.Restore)
+		switch {
+		case restore == "" && attempt == 0:
+			// Not yet requested: kick off the restore.
+			_, err := b.Client.RestoreObject(ctx, &s3.RestoreObjectInput{
+				Bucket: &b.Bucket,
+				Key:    &key,
+				RestoreRequest: &types.RestoreRequest{
+					Days: aws.Int32(1),
+					GlacierJobParameters: &types.GlacierJobParameters{
+						Tier: types.TierStandard,
+					},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to request restore for %s: %w", key, err)
+			}
+		case containsOngoingRequestFalse(restore):
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+	return fmt.Errorf("restore for %s did not complete after %d polls; re-run restore later", key, maxAttempts)
+}
+
+// containsOngoingRequestFalse reports whether the x-amz-restore header value
+// indicates the restore has finished (ongoing-request="false").
+func containsOngoingRequestFalse(restoreHeader string) bool {
+	return restoreHeader != "" && !strings.Contains(restoreHeader, `ongoing-request="true"`)
+}
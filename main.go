@@ -2,180 +2,638 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+
+	"github.com/Tetuwo181/S3archiver/archive"
+	"github.com/Tetuwo181/S3archiver/archive/jsonstore"
+	"github.com/Tetuwo181/S3archiver/archive/sqlitestore"
+	"github.com/Tetuwo181/S3archiver/backend"
+	"github.com/Tetuwo181/S3archiver/backend/local"
+	s3backend "github.com/Tetuwo181/S3archiver/backend/s3"
 )
 
-// S3Uploader manages S3 operations
-type S3Uploader struct {
-	Client *s3.Client
-}
+// ComputeFileHash returns the hex-encoded SHA256 digest of a local file's contents.
+func ComputeFileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-// ArchivedFiles represents the structure of archived files in JSON
-type ArchivedFiles struct {
-	Files []string `json:"files"`
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// LoadArchivedFiles loads archived files from the JSON file
-func LoadArchivedFiles(filename string) (ArchivedFiles, error) {
-	data, err := ioutil.ReadFile(filename)
+// DetectContentType guesses a file's Content-Type from its extension,
+// falling back to sniffing the first 512 bytes of its contents.
+func DetectContentType(path string) (string, error) {
+	if ext := filepath.Ext(path); ext != "" {
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t, nil
+		}
+	}
+
+	file, err := os.Open(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return ArchivedFiles{Files: []string{}}, nil // Return empty list if file doesn't exist
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// EnsureArchiveDirectory ensures the archive directory exists
+func EnsureArchiveDirectory(directory string) error {
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		return os.MkdirAll(directory, 0755)
+	}
+	return nil
+}
+
+// GenerateArchiveFilePath generates a file path for the archive JSON
+func GenerateArchiveFilePath(archiveDir, localDir string) string {
+	// Replace path separators with "_" and ":" with "-"
+	baseName := strings.ReplaceAll(localDir, string(os.PathSeparator), "_")
+	baseName = strings.ReplaceAll(baseName, ":", "-")
+	return filepath.Join(archiveDir, baseName+".json")
+}
+
+// relativeKey converts a path under base into the "/"-separated key used
+// both as the S3/backend key and the archive index key.
+func relativeKey(base, path string) string {
+	rel := strings.TrimPrefix(path, base)
+	rel = strings.TrimPrefix(rel, string(os.PathSeparator))
+	return strings.ReplaceAll(rel, string(os.PathSeparator), "/")
+}
+
+// uploadJob is a single local file discovered during the directory walk,
+// paired with the S3 key it should be uploaded under.
+type uploadJob struct {
+	localPath string
+	s3Key     string
+}
+
+// uploadResult is the outcome of processing one uploadJob.
+type uploadResult struct {
+	key string
+	err error
+}
+
+// runUploadWorkers fans jobs out across a pool of workers, each of which
+// hashes the local file, skips it if the hash and storage options match the
+// archived record, and otherwise uploads it via the backend and records the
+// result in the archive store immediately (so progress survives a crash).
+func runUploadWorkers(ctx context.Context, store backend.Backend, archiveStore archive.Store, jobs []uploadJob, workers int, putOpts backend.PutOptions) error {
+	jobCh := make(chan uploadJob)
+	resultCh := make(chan uploadResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				err := processUploadJob(ctx, store, archiveStore, job, putOpts)
+				resultCh <- uploadResult{key: job.s3Key, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			jobCh <- job
+		}
+		close(jobCh)
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	var firstErr error
+	for result := range resultCh {
+		if result.err != nil {
+			log.Printf("Error processing %s: %v", result.key, result.err)
+			if firstErr == nil {
+				firstErr = result.err
+			}
 		}
-		return ArchivedFiles{}, err
 	}
+	return firstErr
+}
 
-	var archived ArchivedFiles
-	err = json.Unmarshal(data, &archived)
-	return archived, err
+// sseCustomerKeyFingerprint returns the hex-encoded SHA256 digest of an
+// SSE-C key, so drift in the desired key can be detected without ever
+// persisting the key itself to the archive index.
+func sseCustomerKeyFingerprint(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
 }
 
-// SaveArchivedFiles saves the updated archived files to the JSON file
-func SaveArchivedFiles(filename string, archived ArchivedFiles) error {
-	data, err := json.MarshalIndent(archived, "", "  ")
+// processUploadJob computes the local file's hash, skips the upload if it
+// and the desired storage class/encryption match the archived record, and
+// otherwise uploads it and records the refreshed metadata in archiveStore.
+func processUploadJob(ctx context.Context, store backend.Backend, archiveStore archive.Store, job uploadJob, putOpts backend.PutOptions) error {
+	info, err := os.Stat(job.localPath)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filename, data, 0644)
-}
 
-// ListS3Files retrieves the list of files from the S3 bucket
-func (u *S3Uploader) ListS3Files(bucket string) ([]string, error) {
-	var files []string
-	paginator := s3.NewListObjectsV2Paginator(u.Client, &s3.ListObjectsV2Input{
-		Bucket: &bucket,
+	hash, err := ComputeFileHash(job.localPath)
+	if err != nil {
+		return err
+	}
+	sseCustomerKeyHash := sseCustomerKeyFingerprint(putOpts.SSECustomerKey)
+
+	existing, ok, err := archiveStore.Get(ctx, job.s3Key)
+	if err != nil {
+		return err
+	}
+	if ok &&
+		existing.Hash == hash &&
+		existing.StorageClass == putOpts.StorageClass &&
+		existing.ServerSideEncryption == putOpts.ServerSideEncryption &&
+		existing.SSEKMSKeyID == putOpts.SSEKMSKeyID &&
+		existing.SSECustomerKeyHash == sseCustomerKeyHash {
+		log.Printf("Skipping %s: content and storage options unchanged since last archive", job.s3Key)
+		return nil
+	}
+
+	contentType, err := DetectContentType(job.localPath)
+	if err != nil {
+		return err
+	}
+	opts := putOpts
+	opts.ContentType = contentType
+
+	if err := store.Put(ctx, job.s3Key, job.localPath, opts); err != nil {
+		return err
+	}
+	log.Printf("Uploaded %s as %s\n", job.localPath, job.s3Key)
+
+	return archiveStore.Put(ctx, archive.FileRecord{
+		Key:                  job.s3Key,
+		Size:                 info.Size(),
+		ModTime:              info.ModTime(),
+		Hash:                 hash,
+		StorageClass:         putOpts.StorageClass,
+		ServerSideEncryption: putOpts.ServerSideEncryption,
+		SSEKMSKeyID:          putOpts.SSEKMSKeyID,
+		SSECustomerKeyHash:   sseCustomerKeyHash,
+		UploadedAt:           time.Now().UTC(),
 	})
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+}
+
+// downloadJob is a single archived record selected for restore, paired with
+// the local path it should be written to.
+type downloadJob struct {
+	key      string
+	destPath string
+	record   archive.FileRecord
+}
+
+// runDownloadWorkers fans download jobs out across a pool of workers,
+// restoring objects out of Glacier/Deep Archive storage first if needed.
+func runDownloadWorkers(ctx context.Context, store backend.Backend, jobs []downloadJob, workers int) error {
+	jobCh := make(chan downloadJob)
+	resultCh := make(chan uploadResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				err := processDownloadJob(ctx, store, job)
+				resultCh <- uploadResult{key: job.key, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			jobCh <- job
+		}
+		close(jobCh)
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	var firstErr error
+	for result := range resultCh {
+		if result.err != nil {
+			log.Printf("Error restoring %s: %v", result.key, result.err)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+		}
+	}
+	return firstErr
+}
+
+// processDownloadJob restores job.key out of cold storage if needed, then
+// downloads it to job.destPath.
+func processDownloadJob(ctx context.Context, store backend.Backend, job downloadJob) error {
+	if s3Store, ok := store.(*s3backend.Backend); ok && s3backend.IsRestoreRequired(job.record.StorageClass) {
+		log.Printf("Restoring %s from %s, this may take a while...", job.key, job.record.StorageClass)
+		if err := s3Store.RestoreAndWait(ctx, job.key, 30*time.Second, 120); err != nil {
+			return err
+		}
+	}
+
+	if err := store.Get(ctx, job.key, job.destPath); err != nil {
+		return err
+	}
+	log.Printf("Restored %s to %s\n", job.key, job.destPath)
+	return nil
+}
+
+// newBackend constructs the storage backend selected by -backend.
+func newBackend(ctx context.Context, kind string, cfg backendConfig) (backend.Backend, error) {
+	switch kind {
+	case "s3":
+		awsCfg, err := loadAWSConfig(ctx, cfg)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to load AWS config: %w", err)
+		}
+		client := awss3.NewFromConfig(awsCfg, func(o *awss3.Options) {
+			if cfg.endpoint != "" || cfg.forcePathStyle {
+				o.EndpointResolverV2 = &customEndpointResolverV2{
+					endpoint:       cfg.endpoint,
+					disableSSL:     cfg.disableSSL,
+					forcePathStyle: cfg.forcePathStyle,
+				}
+			}
+		})
+		return s3backend.New(client, cfg.bucket, cfg.partSizeMB*1024*1024, cfg.workers), nil
+	case "local":
+		if cfg.backendDir == "" {
+			return nil, fmt.Errorf("-backend-dir is required when -backend=local")
+		}
+		return local.New(cfg.backendDir), nil
+	default:
+		return nil, fmt.Errorf("unknown -backend %q (expected s3 or local)", kind)
+	}
+}
+
+// backendConfig holds the flags needed to construct any supported backend.
+type backendConfig struct {
+	credFile       string
+	region         string
+	bucket         string
+	backendDir     string
+	workers        int
+	partSizeMB     int64
+	endpoint       string
+	forcePathStyle bool
+	disableSSL     bool
+	profile        string
+	accessKey      string
+	secretKey      string
+	sessionToken   string
+}
+
+// loadAWSConfig loads the AWS configuration from, in order of precedence,
+// static credentials, a named shared-config profile, a specific shared
+// credentials file, or the default credential chain.
+func loadAWSConfig(ctx context.Context, cfg backendConfig) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	opts = append(opts, config.WithRegion(cfg.region))
+
+	switch {
+	case cfg.accessKey != "" || cfg.secretKey != "":
+		log.Println("Using static credentials supplied via flags")
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.accessKey, cfg.secretKey, cfg.sessionToken),
+		))
+	case cfg.profile != "":
+		log.Printf("Using shared-credentials profile %q\n", cfg.profile)
+		opts = append(opts, config.WithSharedConfigProfile(cfg.profile))
+	case cfg.credFile != "":
+		log.Printf("Using specified AWS credentials file (%s)\n", cfg.credFile)
+		opts = append(opts, config.WithSharedCredentialsFiles([]string{cfg.credFile}))
+	default:
+		log.Println("Using default AWS credentials file (~/.aws/credentials)")
+	}
+
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// customEndpointResolverV2 overrides the S3 endpoint and path-style
+// behavior (for MinIO, Ceph RGW, Wasabi, Backblaze B2, LocalStack, etc.)
+// while delegating everything else to the default S3 endpoint resolver.
+type customEndpointResolverV2 struct {
+	endpoint       string
+	disableSSL     bool
+	forcePathStyle bool
+}
+
+func (r *customEndpointResolverV2) ResolveEndpoint(ctx context.Context, params awss3.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	if r.forcePathStyle {
+		params.ForcePathStyle = aws.Bool(true)
+	}
+	if r.endpoint != "" {
+		endpoint := r.endpoint
+		if !strings.Contains(endpoint, "://") {
+			scheme := "https://"
+			if r.disableSSL {
+				scheme = "http://"
+			}
+			endpoint = scheme + endpoint
 		}
-		for _, obj := range page.Contents {
-			files = append(files, *obj.Key)
+		params.Endpoint = aws.String(endpoint)
+	}
+	return awss3.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, params)
+}
+
+// openArchiveStore opens the archive index in the requested format,
+// automatically migrating a legacy JSON archive into a fresh SQLite
+// database the first time -archive-format=sqlite is used against it.
+func openArchiveStore(format, path string) (archive.Store, error) {
+	switch format {
+	case "json":
+		return jsonstore.Open(path)
+	case "sqlite":
+		dbPath := sqlitePathFor(path)
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			jsonPath := jsonPathFor(path)
+			if _, err := os.Stat(jsonPath); err == nil {
+				if err := migrateJSONToSQLite(jsonPath, dbPath); err != nil {
+					return nil, fmt.Errorf("failed to migrate legacy JSON archive to sqlite: %w", err)
+				}
+			}
 		}
+		return sqlitestore.Open(dbPath)
+	default:
+		return nil, fmt.Errorf("unknown -archive-format %q (expected json or sqlite)", format)
+	}
+}
+
+// sqlitePathFor derives the SQLite database path for a given -archive path.
+func sqlitePathFor(path string) string {
+	if strings.HasSuffix(path, ".db") {
+		return path
+	}
+	if strings.HasSuffix(path, ".json") {
+		return strings.TrimSuffix(path, ".json") + ".db"
+	}
+	return path + ".db"
+}
+
+// jsonPathFor derives the legacy JSON archive path that a SQLite archive at
+// path would have been migrated from.
+func jsonPathFor(path string) string {
+	if strings.HasSuffix(path, ".db") {
+		return strings.TrimSuffix(path, ".db") + ".json"
 	}
-	return files, nil
+	return path
 }
 
-// UploadFile uploads a file to S3
-func (u *S3Uploader) UploadFile(localPath, s3Key string, bucket string) error {
-	file, err := os.Open(localPath)
+// migrateJSONToSQLite performs a one-shot migration of every record in the
+// JSON archive at jsonPath into a new SQLite database at dbPath.
+func migrateJSONToSQLite(jsonPath, dbPath string) error {
+	legacy, err := jsonstore.Open(jsonPath)
 	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", localPath, err)
+		return err
 	}
-	defer file.Close()
+	defer legacy.Close()
 
-	_, err = u.Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: &bucket,
-		Key:    &s3Key,
-		Body:   file,
-	})
+	records, err := legacy.All(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	store, err := sqlitestore.Open(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to upload %s to S3: %w", s3Key, err)
+		return err
+	}
+	defer store.Close()
+
+	for _, record := range records {
+		if err := store.Put(context.TODO(), record); err != nil {
+			return err
+		}
 	}
-	log.Printf("Uploaded %s to S3 as %s\n", localPath, s3Key)
+	log.Printf("Migrated %d entries from legacy JSON archive %s to sqlite archive %s", len(records), jsonPath, dbPath)
 	return nil
 }
 
-// EnsureArchiveDirectory ensures the archive directory exists
-func EnsureArchiveDirectory(directory string) error {
-	if _, err := os.Stat(directory); os.IsNotExist(err) {
-		return os.MkdirAll(directory, 0755)
+// connFlags holds the backend/auth/endpoint flags shared by the archive and
+// restore subcommands, both of which need to talk to a storage backend.
+type connFlags struct {
+	backendKind    *string
+	credFile       *string
+	bucketName     *string
+	region         *string
+	backendDir     *string
+	workers        *int
+	partSizeMB     *int64
+	endpoint       *string
+	forcePathStyle *bool
+	disableSSL     *bool
+	profile        *string
+	accessKey      *string
+	secretKey      *string
+	sessionToken   *string
+}
+
+// registerConnFlags registers the shared backend/auth/endpoint flags on fs.
+func registerConnFlags(fs *flag.FlagSet) *connFlags {
+	return &connFlags{
+		backendKind:    fs.String("backend", "s3", "Storage backend to use: s3 or local"),
+		credFile:       fs.String("cred", "", "Path to AWS credentials file (optional, defaults to ~/.aws/credentials)"),
+		bucketName:     fs.String("bucket", "", "Name of the S3 bucket (required when -backend=s3)"),
+		region:         fs.String("region", "ap-northeast-1", "AWS region (default: ap-northeast-1)"),
+		backendDir:     fs.String("backend-dir", "", "Destination directory to mirror files into (required when -backend=local)"),
+		workers:        fs.Int("workers", 4, "Number of concurrent workers"),
+		partSizeMB:     fs.Int64("part-size", 5, "Multipart transfer part size in MB (minimum 5, s3 backend only)"),
+		endpoint:       fs.String("endpoint", "", "Custom S3-compatible endpoint (MinIO, Ceph RGW, Wasabi, Backblaze B2, LocalStack, ...)"),
+		forcePathStyle: fs.Bool("force-path-style", false, "Use path-style addressing instead of virtual-hosted-style (required by most S3-compatible endpoints)"),
+		disableSSL:     fs.Bool("disable-ssl", false, "Use plain HTTP instead of HTTPS when talking to -endpoint (ignored if -endpoint already includes a scheme)"),
+		profile:        fs.String("profile", "", "Named shared-credentials/config profile to use"),
+		accessKey:      fs.String("access-key", "", "Static AWS access key ID (overrides -profile/-cred)"),
+		secretKey:      fs.String("secret-key", "", "Static AWS secret access key"),
+		sessionToken:   fs.String("session-token", "", "Static AWS session token (optional, for temporary credentials)"),
 	}
-	return nil
 }
 
-// GenerateArchiveFilePath generates a file path for the archive JSON
-func GenerateArchiveFilePath(archiveDir, localDir string) string {
-	// Replace path separators with "_" and ":" with "-"
-	baseName := strings.ReplaceAll(localDir, string(os.PathSeparator), "_")
-	baseName = strings.ReplaceAll(baseName, ":", "-")
-	return filepath.Join(archiveDir, baseName+".json")
+// toBackendConfig validates the required flags for *f.backendKind and
+// converts f into a backendConfig.
+func (f *connFlags) toBackendConfig() (backendConfig, error) {
+	if *f.backendKind == "s3" && *f.bucketName == "" {
+		return backendConfig{}, fmt.Errorf("-bucket is required when -backend=s3")
+	}
+	if *f.workers < 1 {
+		return backendConfig{}, fmt.Errorf("-workers must be at least 1")
+	}
+	if *f.partSizeMB < 5 {
+		return backendConfig{}, fmt.Errorf("-part-size must be at least 5 MB")
+	}
+	return backendConfig{
+		credFile:       *f.credFile,
+		region:         *f.region,
+		bucket:         *f.bucketName,
+		backendDir:     *f.backendDir,
+		workers:        *f.workers,
+		partSizeMB:     *f.partSizeMB,
+		endpoint:       *f.endpoint,
+		forcePathStyle: *f.forcePathStyle,
+		disableSSL:     *f.disableSSL,
+		profile:        *f.profile,
+		accessKey:      *f.accessKey,
+		secretKey:      *f.secretKey,
+		sessionToken:   *f.sessionToken,
+	}, nil
 }
 
-// Main logic
-func main() {
-	// Parse command-line arguments
-	credFile := flag.String("cred", "", "Path to AWS credentials file (optional, defaults to ~/.aws/credentials)")
-	bucketName := flag.String("bucket", "", "Name of the S3 bucket (required)")
-	region := flag.String("region", "ap-northeast-1", "AWS region (default: ap-northeast-1)")
-	localDirectory := flag.String("local", "", "Local directory to archive (required)")
-	archiveFile := flag.String("archive", "", "Path to archive JSON file (optional)")
-	flag.Parse()
+// archiveFlags holds the -archive/-archive-format flags shared by every
+// subcommand that reads or writes the archive index.
+type archiveFlags struct {
+	archiveFile   *string
+	archiveFormat *string
+}
 
-	// Validate required arguments
-	if *bucketName == "" || *localDirectory == "" {
-		flag.Usage()
-		log.Fatalf("Both -bucket and -local flags are required")
+// registerArchiveFlags registers the shared archive-index flags on fs.
+func registerArchiveFlags(fs *flag.FlagSet) *archiveFlags {
+	return &archiveFlags{
+		archiveFile:   fs.String("archive", "", "Path to archive index file (optional, derived from -local by default)"),
+		archiveFormat: fs.String("archive-format", "json", "Archive index format: json or sqlite"),
 	}
+}
 
-	// Determine archive file path
+// open resolves the archive index path (deriving it from localDir if
+// -archive was not given) and opens it in the requested format.
+func (f *archiveFlags) open(localDir string) (archive.Store, error) {
 	var archiveJSON string
-	if *archiveFile != "" {
-		archiveJSON = *archiveFile
+	if *f.archiveFile != "" {
+		archiveJSON = *f.archiveFile
 	} else {
 		archiveDir := "archives"
 		if err := EnsureArchiveDirectory(archiveDir); err != nil {
-			log.Fatalf("Failed to ensure archive directory: %v", err)
+			return nil, fmt.Errorf("failed to ensure archive directory: %w", err)
 		}
-		archiveJSON = GenerateArchiveFilePath(archiveDir, *localDirectory)
+		archiveJSON = GenerateArchiveFilePath(archiveDir, localDir)
 	}
 
-	// Load AWS configuration
-	var cfg aws.Config
-	var err error
+	store, err := openArchiveStore(*f.archiveFormat, archiveJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive index: %w", err)
+	}
+	return store, nil
+}
 
-	if *credFile == "" {
-		// Use default credentials file (~/.aws/credentials)
-		cfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithRegion(*region),
-		)
-		log.Println("Using default AWS credentials file (~/.aws/credentials)")
-	} else {
-		// Use specified credentials file
-		cfg, err = config.LoadDefaultConfig(context.TODO(),
-			config.WithSharedCredentialsFiles([]string{*credFile}),
-			config.WithRegion(*region),
-		)
-		log.Printf("Using specified AWS credentials file (%s)\n", *credFile)
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
 	}
 
+	var err error
+	switch os.Args[1] {
+	case "archive":
+		err = cmdArchive(os.Args[2:])
+	case "restore":
+		err = cmdRestore(os.Args[2:])
+	case "verify":
+		err = cmdVerify(os.Args[2:])
+	case "list":
+		err = cmdList(os.Args[2:])
+	default:
+		printUsage()
+		os.Exit(1)
+	}
 	if err != nil {
-		log.Fatalf("Unable to load AWS config: %v", err)
+		log.Fatal(err)
 	}
-	client := s3.NewFromConfig(cfg)
-	uploader := S3Uploader{Client: client}
+}
 
-	// Load archived files
-	archived, err := LoadArchivedFiles(archiveJSON)
-	if err != nil {
-		log.Fatalf("Failed to load archived files: %v", err)
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: s3archiver <command> [flags]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  archive  Upload a local directory to the backend, skipping unchanged files")
+	fmt.Fprintln(os.Stderr, "  restore  Download archived objects back to disk")
+	fmt.Fprintln(os.Stderr, "  verify   Re-hash local files and report drift from the archive index")
+	fmt.Fprintln(os.Stderr, "  list     List the contents of the archive index")
+}
+
+// cmdArchive implements the "archive" subcommand: walk -local, upload
+// whatever has changed, and record the results in the archive index.
+func cmdArchive(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	conn := registerConnFlags(fs)
+	archiveFl := registerArchiveFlags(fs)
+	localDirectory := fs.String("local", "", "Local directory to archive (required)")
+	storageClass := fs.String("storage-class", "", "S3 storage class (e.g. STANDARD_IA, GLACIER, DEEP_ARCHIVE, INTELLIGENT_TIERING)")
+	sse := fs.String("sse", "", "Server-side encryption mode: AES256 or aws:kms")
+	kmsKeyID := fs.String("kms-key-id", "", "KMS key ID to use when -sse=aws:kms")
+	sseC := fs.String("sse-c", "", "Path to a file containing a customer-provided SSE-C encryption key")
+	acl := fs.String("acl", "", "Canned ACL to apply to uploaded objects (e.g. private, public-read)")
+	cacheControl := fs.String("cache-control", "", "Cache-Control header to set on uploaded objects")
+	contentDisposition := fs.String("content-disposition", "", "Content-Disposition header to set on uploaded objects")
+	fs.Parse(args)
+
+	if *localDirectory == "" {
+		fs.Usage()
+		return fmt.Errorf("-local is required")
+	}
+	if *sse != "" && *sse != "AES256" && *sse != "aws:kms" {
+		return fmt.Errorf("-sse must be AES256 or aws:kms")
 	}
 
-	// Fetch file list from S3
-	s3Files, err := uploader.ListS3Files(*bucketName)
+	var sseCustomerKey []byte
+	if *sseC != "" {
+		var err error
+		sseCustomerKey, err = ioutil.ReadFile(*sseC)
+		if err != nil {
+			return fmt.Errorf("failed to read -sse-c key file: %w", err)
+		}
+	}
+
+	backendCfg, err := conn.toBackendConfig()
 	if err != nil {
-		log.Fatalf("Failed to list files in S3 bucket: %v", err)
+		return err
+	}
+	store, err := newBackend(context.TODO(), *conn.backendKind, backendCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s backend: %w", *conn.backendKind, err)
 	}
 
-	// Convert S3 files to a map for quick lookup
-	s3FileMap := make(map[string]bool)
-	for _, s3File := range s3Files {
-		s3FileMap[s3File] = true
+	archiveStore, err := archiveFl.open(*localDirectory)
+	if err != nil {
+		return err
 	}
+	defer archiveStore.Close()
 
-	// Scan local files and compare with S3 and archived files
+	var jobs []uploadJob
 	err = filepath.Walk(*localDirectory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -183,42 +641,207 @@ func main() {
 		if info.IsDir() {
 			return nil
 		}
+		jobs = append(jobs, uploadJob{localPath: path, s3Key: relativeKey(*localDirectory, path)})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error scanning local files: %w", err)
+	}
 
-		// Get relative path for S3 key
-		relativePath := strings.TrimPrefix(path, *localDirectory)
-		relativePath = strings.TrimPrefix(relativePath, string(os.PathSeparator))
-		s3Key := strings.ReplaceAll(relativePath, string(os.PathSeparator), "/")
+	putOpts := backend.PutOptions{
+		ContentDisposition:   *contentDisposition,
+		CacheControl:         *cacheControl,
+		ACL:                  *acl,
+		StorageClass:         *storageClass,
+		ServerSideEncryption: *sse,
+		SSEKMSKeyID:          *kmsKeyID,
+		SSECustomerKey:       sseCustomerKey,
+	}
 
-		// Check if file is already in S3 or archived
-		if s3FileMap[s3Key] {
-			log.Printf("Skipping %s: already exists in S3", s3Key)
-			return nil
+	if err := runUploadWorkers(context.TODO(), store, archiveStore, jobs, *conn.workers, putOpts); err != nil {
+		return fmt.Errorf("error uploading files: %w", err)
+	}
+
+	log.Println("Process completed successfully!")
+	return nil
+}
+
+// cmdRestore implements the "restore" subcommand: download archived objects
+// back to -dest, restoring Glacier/Deep Archive objects first if needed.
+func cmdRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	conn := registerConnFlags(fs)
+	archiveFl := registerArchiveFlags(fs)
+	localDirectory := fs.String("local", "", "Local directory the archive index was built from (required)")
+	dest := fs.String("dest", "", "Destination directory to restore files into (required)")
+	prefix := fs.String("prefix", "", "Only restore keys with this prefix")
+	glob := fs.String("glob", "", "Only restore keys matching this glob pattern")
+	fs.Parse(args)
+
+	if *localDirectory == "" {
+		fs.Usage()
+		return fmt.Errorf("-local is required")
+	}
+	if *dest == "" {
+		fs.Usage()
+		return fmt.Errorf("-dest is required")
+	}
+
+	backendCfg, err := conn.toBackendConfig()
+	if err != nil {
+		return err
+	}
+	store, err := newBackend(context.TODO(), *conn.backendKind, backendCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s backend: %w", *conn.backendKind, err)
+	}
+
+	archiveStore, err := archiveFl.open(*localDirectory)
+	if err != nil {
+		return err
+	}
+	defer archiveStore.Close()
+
+	records, err := archiveStore.All(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	var jobs []downloadJob
+	for key, record := range records {
+		if *prefix != "" && !strings.HasPrefix(key, *prefix) {
+			continue
 		}
-		for _, archivedFile := range archived.Files {
-			if archivedFile == s3Key {
-				log.Printf("Skipping %s: already archived", s3Key)
-				return nil
+		if *glob != "" {
+			matched, err := filepath.Match(*glob, key)
+			if err != nil {
+				return fmt.Errorf("invalid -glob pattern: %w", err)
+			}
+			if !matched {
+				continue
 			}
 		}
+		jobs = append(jobs, downloadJob{
+			key:      key,
+			destPath: filepath.Join(*dest, filepath.FromSlash(key)),
+			record:   record,
+		})
+	}
+
+	if err := runDownloadWorkers(context.TODO(), store, jobs, *conn.workers); err != nil {
+		return fmt.Errorf("error restoring files: %w", err)
+	}
+
+	log.Printf("Restored %d object(s) to %s\n", len(jobs), *dest)
+	return nil
+}
+
+// cmdVerify implements the "verify" subcommand: re-hash -local and report
+// any file that is missing from the archive index or whose content has
+// drifted since it was archived.
+func cmdVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	archiveFl := registerArchiveFlags(fs)
+	localDirectory := fs.String("local", "", "Local directory to verify (required)")
+	fs.Parse(args)
+
+	if *localDirectory == "" {
+		fs.Usage()
+		return fmt.Errorf("-local is required")
+	}
+
+	archiveStore, err := archiveFl.open(*localDirectory)
+	if err != nil {
+		return err
+	}
+	defer archiveStore.Close()
+
+	drift := false
+	err = filepath.Walk(*localDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
 
-		// Upload the file
-		if err := uploader.UploadFile(path, s3Key, *bucketName); err != nil {
+		key := relativeKey(*localDirectory, path)
+		hash, err := ComputeFileHash(path)
+		if err != nil {
 			return err
 		}
 
-		// Add the file to the archived list
-		archived.Files = append(archived.Files, s3Key)
+		record, ok, err := archiveStore.Get(context.TODO(), key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !ok:
+			log.Printf("%s: not archived", key)
+			drift = true
+		case record.Hash != hash:
+			log.Printf("%s: content changed since archive", key)
+			drift = true
+		}
 		return nil
 	})
 	if err != nil {
-		log.Fatalf("Error scanning local files: %v", err)
+		return fmt.Errorf("error scanning local files: %w", err)
+	}
+
+	if drift {
+		return fmt.Errorf("verify found drift between %s and the archive index", *localDirectory)
 	}
+	log.Println("No drift found.")
+	return nil
+}
+
+// cmdList implements the "list" subcommand: print the contents of the
+// archive index, optionally filtered by key prefix or recency.
+func cmdList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	archiveFl := registerArchiveFlags(fs)
+	localDirectory := fs.String("local", "", "Local directory the archive index was built from (required)")
+	prefix := fs.String("prefix", "", "Only list keys with this prefix")
+	since := fs.Duration("since", 0, "Only list objects uploaded within this duration (e.g. 168h for the last week)")
+	fs.Parse(args)
 
-	// Save updated archived files
-	err = SaveArchivedFiles(archiveJSON, archived)
+	if *localDirectory == "" {
+		fs.Usage()
+		return fmt.Errorf("-local is required")
+	}
+
+	archiveStore, err := archiveFl.open(*localDirectory)
 	if err != nil {
-		log.Fatalf("Failed to save archived files: %v", err)
+		return err
 	}
+	defer archiveStore.Close()
 
-	log.Println("Process completed successfully!")
+	records, err := archiveStore.All(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	cutoff := time.Time{}
+	if *since > 0 {
+		cutoff = time.Now().Add(-*since)
+	}
+
+	keys := make([]string, 0, len(records))
+	for key, record := range records {
+		if *prefix != "" && !strings.HasPrefix(key, *prefix) {
+			continue
+		}
+		if !cutoff.IsZero() && record.UploadedAt.Before(cutoff) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		record := records[key]
+		fmt.Printf("%s\t%d\t%s\t%s\t%s\n", record.Key, record.Size, record.StorageClass, record.ModTime.Format(time.RFC3339), record.UploadedAt.Format(time.RFC3339))
+	}
+	return nil
 }